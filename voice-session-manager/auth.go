@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey string
+
+const sessionClaimsContextKey contextKey = "sessionClaims"
+
+func contextWithSessionClaims(ctx context.Context, claims *SessionClaims) context.Context {
+	return context.WithValue(ctx, sessionClaimsContextKey, claims)
+}
+
+// sessionClaimsFromContext returns the SessionClaims attached by requireJWT,
+// if any.
+func sessionClaimsFromContext(ctx context.Context) (*SessionClaims, bool) {
+	claims, ok := ctx.Value(sessionClaimsContextKey).(*SessionClaims)
+	return claims, ok
+}
+
+var (
+	// jwksURL and jwtIssuer/jwtAudience configure how AccessTokens are verified.
+	// HS256 is only accepted when jwtHMACSecret is set, for service-to-service callers
+	// that don't have a JWKS endpoint.
+	jwksURL       = os.Getenv("JWKS_URL")
+	jwtIssuer     = os.Getenv("JWT_ISSUER")
+	jwtAudience   = os.Getenv("JWT_AUDIENCE")
+	jwtHMACSecret = []byte(os.Getenv("JWT_HMAC_SECRET"))
+
+	jwks        *keyfunc.JWKS
+	jwksRefresh = 10 * time.Minute
+)
+
+// SessionClaims is the subset of a validated AccessToken that we persist
+// alongside the session record and use for scope checks.
+type SessionClaims struct {
+	Subject string   `json:"subject"`
+	Issuer  string   `json:"issuer"`
+	Scopes  []string `json:"scopes"`
+}
+
+type accessTokenClaims struct {
+	Scope string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+func initJWKS() error {
+	if jwksURL == "" {
+		log.Println("JWKS_URL not set, only HS256 access tokens will be accepted")
+		return nil
+	}
+
+	var err error
+	jwks, err = keyfunc.Get(jwksURL, keyfunc.Options{
+		RefreshInterval:   jwksRefresh,
+		RefreshRateLimit:  time.Minute,
+		RefreshErrorHandler: func(err error) {
+			log.Printf("JWKS refresh failed: %v", err)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("fetching JWKS from %s: %w", jwksURL, err)
+	}
+
+	log.Printf("Loaded JWKS from %s", jwksURL)
+	return nil
+}
+
+// validateAccessToken parses and verifies an AccessToken, returning the
+// session claims on success. It accepts RS256/ES256 tokens verified against
+// the configured JWKS, and HS256 tokens verified against JWT_HMAC_SECRET.
+func validateAccessToken(tokenString string) (*SessionClaims, error) {
+	if tokenString == "" {
+		return nil, errors.New("access token is empty")
+	}
+
+	claims := &accessTokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case "RS256", "ES256":
+			if jwks == nil {
+				return nil, errors.New("no JWKS configured for RS256/ES256 tokens")
+			}
+			return jwks.Keyfunc(t)
+		case "HS256":
+			if len(jwtHMACSecret) == 0 {
+				return nil, errors.New("no HMAC secret configured for HS256 tokens")
+			}
+			return jwtHMACSecret, nil
+		default:
+			return nil, fmt.Errorf("unsupported signing method %q", t.Method.Alg())
+		}
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("parsing access token: %w", err)
+	}
+	if !token.Valid {
+		return nil, errors.New("access token is not valid")
+	}
+
+	if jwtIssuer != "" && claims.Issuer != jwtIssuer {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if jwtAudience != "" && !slices.Contains(claims.Audience, jwtAudience) {
+		return nil, fmt.Errorf("token not valid for audience %q", jwtAudience)
+	}
+
+	var scopes []string
+	if claims.Scope != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+
+	return &SessionClaims{
+		Subject: claims.Subject,
+		Issuer:  claims.Issuer,
+		Scopes:  scopes,
+	}, nil
+}
+
+func hasScope(claims *SessionClaims, scope string) bool {
+	for _, s := range claims.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// requireJWT is middleware that verifies the bearer AccessToken in the
+// Authorization header and, if scopes are given, that the token carries all
+// of them. On success the parsed SessionClaims are attached to the request
+// context under sessionClaimsContextKey.
+func requireJWT(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			environment := os.Getenv("ENVIRONMENT")
+
+			authHeader := r.Header.Get("Authorization")
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == authHeader || tokenString == "" {
+				tokenValidatedCounter.WithLabelValues(environment, "missing", "", "").Inc()
+				http.Error(w, "Bearer token required", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := validateAccessToken(tokenString)
+			if err != nil {
+				tokenValidatedCounter.WithLabelValues(environment, "invalid", "", "").Inc()
+				http.Error(w, "Invalid or expired access token", http.StatusUnauthorized)
+				return
+			}
+
+			for _, scope := range scopes {
+				if !hasScope(claims, scope) {
+					tokenValidatedCounter.WithLabelValues(environment, "forbidden", claims.Issuer, scope).Inc()
+					http.Error(w, fmt.Sprintf("missing required scope %q", scope), http.StatusForbidden)
+					return
+				}
+			}
+
+			scopeLabel := ""
+			if len(claims.Scopes) > 0 {
+				scopeLabel = claims.Scopes[0]
+			}
+			tokenValidatedCounter.WithLabelValues(environment, "valid", claims.Issuer, scopeLabel).Inc()
+
+			ctx := contextWithSessionClaims(r.Context(), claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
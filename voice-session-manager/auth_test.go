@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func withAuthConfig(t *testing.T, issuer, audience string, secret []byte) {
+	t.Helper()
+
+	prevIssuer, prevAudience, prevSecret := jwtIssuer, jwtAudience, jwtHMACSecret
+	jwtIssuer, jwtAudience, jwtHMACSecret = issuer, audience, secret
+
+	t.Cleanup(func() {
+		jwtIssuer, jwtAudience, jwtHMACSecret = prevIssuer, prevAudience, prevSecret
+	})
+}
+
+func signHS256(t *testing.T, secret []byte, claims accessTokenClaims) string {
+	t.Helper()
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("signing test token: %v", err)
+	}
+	return signed
+}
+
+func TestValidateAccessTokenHS256Valid(t *testing.T) {
+	secret := []byte("test-secret")
+	withAuthConfig(t, "voice-issuer", "voice-audience", secret)
+
+	token := signHS256(t, secret, accessTokenClaims{
+		Scope: "call:read call:write",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "agent-1",
+			Issuer:    "voice-issuer",
+			Audience:  jwt.ClaimStrings{"voice-audience"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	claims, err := validateAccessToken(token)
+	if err != nil {
+		t.Fatalf("expected valid token, got error: %v", err)
+	}
+	if claims.Subject != "agent-1" {
+		t.Errorf("Subject = %q, want %q", claims.Subject, "agent-1")
+	}
+	if !hasScope(claims, "call:write") {
+		t.Errorf("expected scope call:write in %v", claims.Scopes)
+	}
+}
+
+func TestValidateAccessTokenExpired(t *testing.T) {
+	secret := []byte("test-secret")
+	withAuthConfig(t, "", "", secret)
+
+	token := signHS256(t, secret, accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "agent-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		},
+	})
+
+	if _, err := validateAccessToken(token); err == nil {
+		t.Fatal("expected expired token to be rejected")
+	}
+}
+
+func TestValidateAccessTokenWrongAudience(t *testing.T) {
+	secret := []byte("test-secret")
+	withAuthConfig(t, "", "expected-audience", secret)
+
+	token := signHS256(t, secret, accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "agent-1",
+			Audience:  jwt.ClaimStrings{"other-audience"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	if _, err := validateAccessToken(token); err == nil {
+		t.Fatal("expected token with wrong audience to be rejected")
+	}
+}
+
+func TestValidateAccessTokenWrongIssuer(t *testing.T) {
+	secret := []byte("test-secret")
+	withAuthConfig(t, "expected-issuer", "", secret)
+
+	token := signHS256(t, secret, accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "agent-1",
+			Issuer:    "other-issuer",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	if _, err := validateAccessToken(token); err == nil {
+		t.Fatal("expected token with wrong issuer to be rejected")
+	}
+}
+
+func TestValidateAccessTokenNoHMACSecretConfigured(t *testing.T) {
+	withAuthConfig(t, "", "", nil)
+
+	token := signHS256(t, []byte("whatever-the-caller-used"), accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "agent-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	if _, err := validateAccessToken(token); err == nil {
+		t.Fatal("expected HS256 token to be rejected when no HMAC secret is configured")
+	}
+}
+
+func TestValidateAccessTokenEmpty(t *testing.T) {
+	if _, err := validateAccessToken(""); err == nil {
+		t.Fatal("expected empty token to be rejected")
+	}
+}
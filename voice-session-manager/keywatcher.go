@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const sessionRevokedChannel = "session:revoked"
+
+var activeSessionsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "voice_sessions_active",
+	Help: "Number of voice sessions currently active",
+})
+
+func init() {
+	prometheus.MustRegister(activeSessionsGauge)
+}
+
+// startKeyWatcher subscribes to Redis keyspace notifications for expired
+// session keys and to the session:revoked pub/sub channel, analogous to the
+// goredis keywatcher pattern. On either event it tears down any open
+// WebSocket connections for that session and emits a synthetic stop event so
+// downstream consumers see a clean end-of-call.
+//
+// Requires the Redis server to have notify-keyspace-events including "Ex"
+// (expired events) enabled.
+func startKeyWatcher() {
+	if redisClient == nil {
+		return
+	}
+
+	ctx := context.Background()
+
+	expiredPS := redisClient.PSubscribe(ctx, "__keyevent@*__:expired")
+	revokedPS := redisClient.Subscribe(ctx, sessionRevokedChannel)
+
+	go watchExpired(expiredPS)
+	go watchRevoked(revokedPS)
+
+	activeSessionsGauge.Set(0)
+}
+
+func watchExpired(ps *redis.PubSub) {
+	defer ps.Close()
+
+	for msg := range ps.Channel() {
+		key := msg.Payload
+		if !strings.HasPrefix(key, "session:") {
+			continue
+		}
+		sessionID := strings.TrimPrefix(key, "session:")
+		handleSessionEnded(sessionID, "session_expired")
+	}
+}
+
+func watchRevoked(ps *redis.PubSub) {
+	defer ps.Close()
+
+	for msg := range ps.Channel() {
+		handleSessionEnded(msg.Payload, "session_revoked")
+	}
+}
+
+// handleSessionEnded terminates any open WebSocket connections for
+// sessionID, emits a synthetic stop CallEvent carrying errorCode, and
+// decrements the active-sessions gauge.
+func handleSessionEnded(sessionID, errorCode string) {
+	log.Printf("Session %s ended (%s)", sessionID, errorCode)
+
+	hub.closeSession(sessionID)
+
+	errCode := errorCode
+	event := CallEvent{
+		SessionID: sessionID,
+		EventType: "stop",
+		Timestamp: time.Now(),
+		ErrorCode: &errCode,
+	}
+	recordCallEvent(event)
+	publishToFMS(event)
+
+	activeSessionsGauge.Dec()
+}
+
+// revokeSessionHandler publishes sessionId to the revocation channel so
+// every Voice Session Manager pod watching it tears down the session within
+// milliseconds, and removes the session record itself.
+func revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionID := vars["sessionId"]
+	if sessionID == "" {
+		http.Error(w, "Session ID required", http.StatusBadRequest)
+		return
+	}
+
+	if redisClient == nil {
+		http.Error(w, "No session store configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := redisClient.Del(ctx, "session:"+sessionID).Err(); err != nil {
+		log.Printf("Failed to delete revoked session %s: %v", sessionID, err)
+	}
+
+	if err := redisClient.Publish(ctx, sessionRevokedChannel, sessionID).Err(); err != nil {
+		http.Error(w, "Failed to publish revocation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"revoked"}`))
+}
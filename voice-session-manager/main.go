@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -18,8 +22,10 @@ import (
 )
 
 var (
-	// Redis client (optional)
-	redisClient *redis.Client
+	// Redis client (optional). May be a standalone client, a Sentinel-backed
+	// failover client, or a Cluster client depending on configuration - see
+	// initRedis in redis.go.
+	redisClient redis.UniversalClient
 
 	// Prometheus metrics
 	sessionCreatedCounter = prometheus.NewCounterVec(
@@ -35,7 +41,7 @@ var (
 			Name: "voice_tokens_validated_total",
 			Help: "Total number of voice tokens validated",
 		},
-		[]string{"environment", "status"},
+		[]string{"environment", "status", "issuer", "scope"},
 	)
 
 	callDurationHistogram = prometheus.NewHistogramVec(
@@ -100,6 +106,11 @@ func main() {
 	// Initialize Redis if URL provided
 	initRedis()
 
+	// Initialize the JWKS cache used to verify RS256/ES256 access tokens
+	if err := initJWKS(); err != nil {
+		log.Fatalf("Failed to initialize JWKS: %v", err)
+	}
+
 	// Setup router
 	r := mux.NewRouter()
 
@@ -109,9 +120,13 @@ func main() {
 	// API routes
 	r.HandleFunc("/health", healthHandler).Methods("GET")
 	r.HandleFunc("/metrics", promhttp.Handler().ServeHTTP).Methods("GET")
-	r.HandleFunc("/auth/session/create", createSessionHandler).Methods("POST")
-	r.HandleFunc("/call/event", callEventHandler).Methods("POST")
-	r.HandleFunc("/session/{sessionId}/validate", validateSessionHandler).Methods("GET")
+	r.Handle("/auth/session/create", authRateLimitMiddleware(http.HandlerFunc(createSessionHandler))).Methods("POST")
+	r.Handle("/auth/tokens", requireJWT("admin")(http.HandlerFunc(purgeLapsedTokensHandler))).Methods("DELETE")
+	r.Handle("/call/event", requireJWT()(http.HandlerFunc(callEventHandler))).Methods("POST")
+	r.Handle("/session/{sessionId}/validate", requireJWT()(http.HandlerFunc(validateSessionHandler))).Methods("GET")
+	r.Handle("/ws/session/{sessionId}", requireJWT()(http.HandlerFunc(websocketHandler))).Methods("GET")
+	r.Handle("/auth/session/{sessionId}/revoke", requireJWT("session:admin")(http.HandlerFunc(revokeSessionHandler))).Methods("POST")
+	r.HandleFunc("/debug/status", debugStatusHandler).Methods("GET")
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -119,36 +134,48 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Starting Voice Session Manager on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
-}
-
-func initRedis() {
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Println("REDIS_URL not provided, using in-memory session storage")
-		return
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: envDuration("READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       envDuration("READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      envDuration("WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:       envDuration("IDLE_TIMEOUT", 60*time.Second),
 	}
 
-	opt, err := redis.ParseURL(redisURL)
-	if err != nil {
-		log.Printf("Failed to parse Redis URL: %v, using in-memory storage", err)
-		return
-	}
+	go func() {
+		log.Printf("Starting Voice Session Manager on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
 
-	redisClient = redis.NewClient(opt)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	<-ctx.Done()
+	stop()
 
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	log.Println("Shutting down, draining in-flight sessions...")
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), envDuration("SHUTDOWN_DRAIN_TIMEOUT", 30*time.Second))
 	defer cancel()
 
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		log.Printf("Redis connection failed: %v, using in-memory storage", err)
-		redisClient = nil
-		return
+	// srv.Shutdown stops the listener from accepting new connections as
+	// soon as it's called, so it must run concurrently with the drain -
+	// not after it - or new sessions keep being accepted for the whole
+	// drain window. Both are bounded by drainCtx.
+	var drainWG sync.WaitGroup
+	drainWG.Add(1)
+	go func() {
+		defer drainWG.Done()
+		hub.drainAll(drainCtx)
+	}()
+
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Printf("Graceful shutdown failed: %v", err)
 	}
 
-	log.Println("Connected to Redis successfully")
+	drainWG.Wait()
 }
 
 func responseTimeMiddleware(next http.Handler) http.Handler {
@@ -179,6 +206,14 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Hijack passes through to the underlying ResponseWriter so the WebSocket
+// upgrader can still take over the connection through this wrapper - without
+// it, embedding the bare http.ResponseWriter interface hides Hijack and
+// every /ws/session/{sessionId} upgrade fails.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	environment := os.Getenv("ENVIRONMENT")
 	if environment == "" {
@@ -218,20 +253,20 @@ func createSessionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate access token (simplified - in production, validate JWT)
-	if req.AccessToken == "" {
-		tokenValidatedCounter.WithLabelValues(
-			os.Getenv("ENVIRONMENT"),
-			"invalid",
-		).Inc()
-		http.Error(w, "Access token required", http.StatusUnauthorized)
+	environment := os.Getenv("ENVIRONMENT")
+
+	claims, err := validateAccessToken(req.AccessToken)
+	if err != nil {
+		tokenValidatedCounter.WithLabelValues(environment, "invalid", "", "").Inc()
+		http.Error(w, "Invalid or expired access token", http.StatusUnauthorized)
 		return
 	}
 
-	tokenValidatedCounter.WithLabelValues(
-		os.Getenv("ENVIRONMENT"),
-		"valid",
-	).Inc()
+	scopeLabel := ""
+	if len(claims.Scopes) > 0 {
+		scopeLabel = claims.Scopes[0]
+	}
+	tokenValidatedCounter.WithLabelValues(environment, "valid", claims.Issuer, scopeLabel).Inc()
 
 	// Generate session ID
 	sessionID := uuid.New().String()
@@ -242,6 +277,7 @@ func createSessionHandler(w http.ResponseWriter, r *http.Request) {
 		"agent_id":   req.AgentID,
 		"expires_at": expiresAt,
 		"metadata":   req.Metadata,
+		"claims":     claims,
 	}
 
 	if redisClient != nil {
@@ -256,6 +292,7 @@ func createSessionHandler(w http.ResponseWriter, r *http.Request) {
 		os.Getenv("ENVIRONMENT"),
 		getAgentType(req.AgentID),
 	).Inc()
+	activeSessionsGauge.Inc()
 
 	response := SessionResponse{
 		SessionID:   sessionID,
@@ -299,6 +336,33 @@ func validateSessionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// sessionOwner returns the agent_id a session record was created for, so
+// callers can confirm a request's claims.Subject actually owns the session
+// it's trying to act on. Fails closed (false) if the session can't be read -
+// there is no owner to compare against, so the caller must not be trusted.
+func sessionOwner(sessionID string) (string, bool) {
+	if redisClient == nil {
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	raw, err := redisClient.Get(ctx, "session:"+sessionID).Result()
+	if err != nil {
+		return "", false
+	}
+
+	var session struct {
+		AgentID string `json:"agent_id"`
+	}
+	if err := json.Unmarshal([]byte(raw), &session); err != nil {
+		return "", false
+	}
+
+	return session.AgentID, true
+}
+
 func callEventHandler(w http.ResponseWriter, r *http.Request) {
 	var event CallEvent
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
@@ -308,7 +372,35 @@ func callEventHandler(w http.ResponseWriter, r *http.Request) {
 
 	event.Timestamp = time.Now()
 
-	// Record metrics based on event type
+	recordCallEvent(event)
+
+	// Publish to FMS (Flight Memory System) - placeholder
+	publishToFMS(event)
+
+	// Fan out to any WebSocket subscribers watching this session/agent so
+	// HTTP and WS callers share the same event ordering.
+	hub.publish(event)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "received",
+		"timestamp": event.Timestamp,
+	})
+}
+
+// recordCallEvent records metrics and persists event to Redis. It is shared
+// by the HTTP callEventHandler and the WebSocket readPump so both entry
+// points feed the same pipeline.
+func recordCallEvent(event CallEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	recordCallEventContext(ctx, event)
+}
+
+// recordCallEventContext is recordCallEvent with a caller-supplied context,
+// so the graceful-shutdown drain path can bound its Redis writes by the
+// configured drain window instead of each flush opening its own timeout.
+func recordCallEventContext(ctx context.Context, event CallEvent) {
 	switch event.EventType {
 	case "stop":
 		if event.Duration != nil {
@@ -319,24 +411,11 @@ func callEventHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Publish to FMS (Flight Memory System) - placeholder
-	publishToFMS(event)
-
-	// Store in Redis if available
 	if redisClient != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		eventJSON, _ := json.Marshal(event)
 		redisClient.LPush(ctx, "call_events:"+event.SessionID, eventJSON)
 		redisClient.Expire(ctx, "call_events:"+event.SessionID, 24*time.Hour)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "received",
-		"timestamp": event.Timestamp,
-	})
 }
 
 func publishToFMS(event CallEvent) {
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	authRateLimitTotalCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "voice_auth_rate_limited_total",
+		Help: "Total number of createSessionHandler requests rejected by the auth rate limiter",
+	})
+
+	sessionsPurgedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "voice_sessions_purged_total",
+		Help: "Total number of lapsed session keys purged",
+	})
+
+	// authRateLimitCount and authRateLimitWindow are parsed from the
+	// AUTH_RATE_LIMIT environment variable, e.g. "5/30m" meaning 5 attempts
+	// per client IP + agent_id per 30 minutes. There is no command-line flag
+	// for this setting.
+	authRateLimitCount  = 5
+	authRateLimitWindow = 30 * time.Minute
+)
+
+func init() {
+	prometheus.MustRegister(authRateLimitTotalCounter)
+	prometheus.MustRegister(sessionsPurgedCounter)
+
+	if spec := os.Getenv("AUTH_RATE_LIMIT"); spec != "" {
+		if count, window, err := parseRateLimitSpec(spec); err == nil {
+			authRateLimitCount, authRateLimitWindow = count, window
+		} else {
+			log.Printf("Invalid AUTH_RATE_LIMIT %q: %v, using default %d/%s", spec, err, authRateLimitCount, authRateLimitWindow)
+		}
+	}
+}
+
+// parseRateLimitSpec parses a "5/30m" style spec into a count and window.
+func parseRateLimitSpec(spec string) (int, time.Duration, error) {
+	parts := strings.SplitN(spec, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected format COUNT/WINDOW, got %q", spec)
+	}
+
+	count, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid count: %w", err)
+	}
+
+	window, err := time.ParseDuration(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window: %w", err)
+	}
+
+	return count, window, nil
+}
+
+// trustProxyHeaders gates whether X-Forwarded-For is honored at all. It must
+// be explicitly enabled (the service sits behind a load balancer that sets
+// XFF itself and strips/overwrites any client-supplied value) - otherwise
+// any caller could set an arbitrary XFF value and get a fresh rate-limit
+// bucket on every request, defeating the limiter entirely.
+var trustProxyHeaders = os.Getenv("TRUST_PROXY_HEADERS") == "true"
+
+// clientIP extracts the caller's IP for rate-limit bucketing. X-Forwarded-For
+// is only honored when TRUST_PROXY_HEADERS=true; otherwise callers are keyed
+// on the connection's own RemoteAddr, which they cannot spoof.
+func clientIP(r *http.Request) string {
+	if trustProxyHeaders {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// authRateLimitMiddleware enforces a sliding window of authRateLimitCount
+// attempts per authRateLimitWindow, keyed on client IP + agent_id, using a
+// Redis sorted set as the shared counter across all pods. Falls open (does
+// not rate-limit) when Redis is unavailable, since createSessionHandler's
+// own JWT validation still provides the primary defense.
+func authRateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if redisClient == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bodyBytes, err := io.ReadAll(r.Body)
+		if err == nil {
+			r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		}
+
+		var req SessionRequest
+		json.Unmarshal(bodyBytes, &req)
+
+		key := fmt.Sprintf("auth_rate_limit:%s:%s", clientIP(r), req.AgentID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		windowStart := now.Add(-authRateLimitWindow)
+
+		pipe := redisClient.TxPipeline()
+		pipe.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(windowStart.UnixNano(), 10))
+		countCmd := pipe.ZCard(ctx, key)
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now.UnixNano()), Member: now.UnixNano()})
+		pipe.Expire(ctx, key, authRateLimitWindow)
+		if _, err := pipe.Exec(ctx); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if countCmd.Val() >= int64(authRateLimitCount) {
+			authRateLimitTotalCounter.Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(authRateLimitWindow.Seconds())))
+			http.Error(w, "Too many authentication attempts", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func purgeLapsedTokensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("scope") != "lapsed" {
+		http.Error(w, `scope query parameter must be "lapsed"`, http.StatusBadRequest)
+		return
+	}
+
+	if redisClient == nil {
+		http.Error(w, "No session store configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var purged int
+	var cursor uint64
+	for {
+		keys, next, err := redisClient.Scan(ctx, cursor, "session:*", 100).Result()
+		if err != nil {
+			http.Error(w, "Failed to scan session keys", http.StatusInternalServerError)
+			return
+		}
+
+		for _, key := range keys {
+			if sessionIsLapsed(ctx, key) {
+				redisClient.Del(ctx, key)
+				// A bare DEL doesn't fire a keyspace "expired" event the
+				// way a natural TTL expiry would, so watchExpired never
+				// sees these - tear the session down the same way it
+				// would for expiry/revocation instead of leaving any open
+				// WS connection and the active-sessions gauge stale.
+				handleSessionEnded(strings.TrimPrefix(key, "session:"), "session_expired")
+				purged++
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	sessionsPurgedCounter.Add(float64(purged))
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"purged":%d}`, purged)
+}
+
+// sessionIsLapsed reports whether a session:* key's stored expires_at is in
+// the past, so the purge can remove records that Redis has not evicted on
+// its own. Records with no expires_at, or that cannot be read or decoded,
+// are never reported as lapsed.
+func sessionIsLapsed(ctx context.Context, key string) bool {
+	raw, err := redisClient.Get(ctx, key).Result()
+	if err != nil {
+		return false
+	}
+
+	var session struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal([]byte(raw), &session); err != nil {
+		return false
+	}
+
+	return !session.ExpiresAt.IsZero() && session.ExpiresAt.Before(time.Now())
+}
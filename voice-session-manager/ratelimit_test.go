@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func withTrustProxyHeaders(t *testing.T, trust bool) {
+	t.Helper()
+
+	prev := trustProxyHeaders
+	trustProxyHeaders = trust
+
+	t.Cleanup(func() {
+		trustProxyHeaders = prev
+	})
+}
+
+func TestClientIPIgnoresForwardedForByDefault(t *testing.T) {
+	withTrustProxyHeaders(t, false)
+
+	req := &http.Request{RemoteAddr: "10.0.0.5:54321"}
+	req.Header = http.Header{}
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if got := clientIP(req); got != "10.0.0.5" {
+		t.Errorf("clientIP() = %q, want %q (X-Forwarded-For should be ignored)", got, "10.0.0.5")
+	}
+}
+
+func TestClientIPHonorsForwardedForWhenTrusted(t *testing.T) {
+	withTrustProxyHeaders(t, true)
+
+	req := &http.Request{RemoteAddr: "10.0.0.5:54321"}
+	req.Header = http.Header{}
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+
+	if got := clientIP(req); got != "1.2.3.4" {
+		t.Errorf("clientIP() = %q, want %q", got, "1.2.3.4")
+	}
+}
+
+func TestParseRateLimitSpec(t *testing.T) {
+	count, window, err := parseRateLimitSpec("5/30m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("count = %d, want 5", count)
+	}
+	if window != 30*time.Minute {
+		t.Errorf("window = %s, want 30m", window)
+	}
+}
+
+func TestParseRateLimitSpecInvalid(t *testing.T) {
+	for _, spec := range []string{"", "5", "5/", "/30m", "abc/30m", "5/abc"} {
+		if _, _, err := parseRateLimitSpec(spec); err == nil {
+			t.Errorf("parseRateLimitSpec(%q) expected an error, got none", spec)
+		}
+	}
+}
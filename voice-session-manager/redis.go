@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisConfig mirrors the shape used by our other Go services: a single
+// struct that can describe a standalone node, a Sentinel-fronted master, or
+// a Cluster, plus the pool knobs that matter for a session store under load.
+type RedisConfig struct {
+	// Addrs is one address for standalone mode, the Sentinel addresses when
+	// SentinelMaster is set, or the full node list for Cluster mode.
+	Addrs          []string
+	SentinelMaster string
+	SentinelPassword string
+	Password       string
+	DB             int
+	MaxIdle        int
+	MaxActive      int
+	ClusterMode    bool
+}
+
+const (
+	redisMaxReconnectBackoff = 30 * time.Second
+	redisInitialBackoff      = 500 * time.Millisecond
+)
+
+func loadRedisConfig() (RedisConfig, bool) {
+	cfg := RedisConfig{
+		SentinelMaster:   os.Getenv("REDIS_SENTINEL_MASTER"),
+		SentinelPassword: os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		Password:         os.Getenv("REDIS_PASSWORD"),
+		DB:               envInt("REDIS_DB", 0),
+		MaxIdle:          envInt("REDIS_MAX_IDLE", 10),
+		MaxActive:        envInt("REDIS_MAX_ACTIVE", 100),
+		ClusterMode:      os.Getenv("REDIS_CLUSTER_NODES") != "",
+	}
+
+	switch {
+	case cfg.ClusterMode:
+		cfg.Addrs = splitAndTrim(os.Getenv("REDIS_CLUSTER_NODES"))
+	case os.Getenv("REDIS_SENTINEL_ADDRS") != "":
+		cfg.Addrs = splitAndTrim(os.Getenv("REDIS_SENTINEL_ADDRS"))
+	case os.Getenv("REDIS_URL") != "":
+		opt, err := redis.ParseURL(os.Getenv("REDIS_URL"))
+		if err != nil {
+			log.Printf("Failed to parse REDIS_URL: %v", err)
+			return RedisConfig{}, false
+		}
+		cfg.Addrs = []string{opt.Addr}
+		if cfg.Password == "" {
+			cfg.Password = opt.Password
+		}
+		if cfg.DB == 0 {
+			cfg.DB = opt.DB
+		}
+	default:
+		return RedisConfig{}, false
+	}
+
+	return cfg, true
+}
+
+func splitAndTrim(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func newUniversalClient(cfg RedisConfig) redis.UniversalClient {
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		MasterName:       cfg.SentinelMaster,
+		SentinelPassword: cfg.SentinelPassword,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		MinIdleConns:     cfg.MaxIdle,
+		PoolSize:         cfg.MaxActive,
+	}
+
+	if cfg.ClusterMode {
+		return redis.NewClusterClient(opts.Cluster())
+	}
+	if cfg.SentinelMaster != "" {
+		return redis.NewFailoverClient(opts.Failover())
+	}
+	return redis.NewClient(opts.Simple())
+}
+
+func initRedis() {
+	cfg, ok := loadRedisConfig()
+	if !ok {
+		if os.Getenv("ENVIRONMENT") == "production" {
+			log.Fatal("No Redis configuration provided and ENVIRONMENT=production: refusing to start with an in-memory session store")
+		}
+		log.Println("No Redis configuration provided, using in-memory session storage")
+		return
+	}
+
+	client := newUniversalClient(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		if os.Getenv("ENVIRONMENT") == "production" {
+			log.Fatalf("Redis connection failed: %v, refusing to start in production without a working session store", err)
+		}
+		log.Printf("Redis connection failed: %v, using in-memory storage", err)
+		return
+	}
+
+	redisClient = client
+	log.Println("Connected to Redis successfully")
+
+	startKeyWatcher()
+	go reconnectOnDisconnect(client)
+}
+
+// reconnectOnDisconnect pings the client on a loop and reconnects with
+// exponential backoff if it goes unreachable, rather than silently falling
+// back to in-memory storage mid-flight.
+func reconnectOnDisconnect(client redis.UniversalClient) {
+	backoff := redisInitialBackoff
+
+	for {
+		time.Sleep(5 * time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := client.Ping(ctx).Err()
+		cancel()
+
+		if err == nil {
+			backoff = redisInitialBackoff
+			continue
+		}
+
+		log.Printf("Redis ping failed: %v, retrying in %s", err, backoff)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > redisMaxReconnectBackoff {
+			backoff = redisMaxReconnectBackoff
+		}
+	}
+}
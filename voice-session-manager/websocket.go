@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// wsQueueSize bounds how many outbound events are buffered per connection.
+	// Once a connection's queue is full the hub drops further events for it
+	// rather than blocking everyone else; the connection stays subscribed.
+	wsQueueSize = envInt("WS_QUEUE_SIZE", 64)
+
+	// wsPingTimeout controls how long the hub waits for a pong before
+	// considering a connection dead.
+	wsPingTimeout = envDuration("WS_PING_TIMEOUT", 30*time.Second)
+
+	// wsWriteTimeout bounds each individual write to a WS connection. It is
+	// set by writePump before every WriteJSON/WriteMessage, since the conn's
+	// write deadline is cleared right after upgrade and is otherwise never
+	// touched again for the life of a long-running stream.
+	wsWriteTimeout = envDuration("WS_WRITE_TIMEOUT", 10*time.Second)
+
+	upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     func(r *http.Request) bool { return true },
+	}
+
+	hub = newEventHub()
+
+	wsRouter = newRouter()
+)
+
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+// router tracks coarse request counters for the /debug/status endpoint.
+type router struct {
+	ReqsReceived int64
+	ReqsActive   int64
+}
+
+func newRouter() *router {
+	return &router{}
+}
+
+func (rt *router) enter() {
+	atomic.AddInt64(&rt.ReqsReceived, 1)
+	atomic.AddInt64(&rt.ReqsActive, 1)
+}
+
+func (rt *router) leave() {
+	atomic.AddInt64(&rt.ReqsActive, -1)
+}
+
+func (rt *router) snapshot() map[string]int64 {
+	return map[string]int64{
+		"reqs_received": atomic.LoadInt64(&rt.ReqsReceived),
+		"reqs_active":   atomic.LoadInt64(&rt.ReqsActive),
+	}
+}
+
+// deadlineTimer is a resettable, idempotent-stop liveness timer: it fires
+// onExpire once after d unless Reset is called again first, or Stop is
+// called to cancel it for good. Modeled on gonet's deadlineTimer so the
+// WebSocket handler can enforce "no pong within N" without racing a Stop
+// against an in-flight expiry.
+type deadlineTimer struct {
+	timer  *time.Timer
+	cancel chan struct{}
+	once   sync.Once
+}
+
+func newDeadlineTimer(d time.Duration, onExpire func()) *deadlineTimer {
+	dt := &deadlineTimer{cancel: make(chan struct{})}
+	dt.timer = time.AfterFunc(d, func() {
+		select {
+		case <-dt.cancel:
+			return
+		default:
+			onExpire()
+		}
+	})
+	return dt
+}
+
+func (dt *deadlineTimer) Reset(d time.Duration) {
+	dt.timer.Reset(d)
+}
+
+func (dt *deadlineTimer) Stop() {
+	dt.once.Do(func() {
+		close(dt.cancel)
+		dt.timer.Stop()
+	})
+}
+
+// subscriber is one open WebSocket connection, keyed on session and agent so
+// the hub can fan events out to everyone watching a session or a given
+// agent's sessions.
+type subscriber struct {
+	sessionID string
+	agentID   string
+	conn      *websocket.Conn
+	send      chan CallEvent
+	deadline  *deadlineTimer
+	closeOnce sync.Once
+}
+
+func (s *subscriber) close() {
+	s.closeOnce.Do(func() {
+		if s.deadline != nil {
+			s.deadline.Stop()
+		}
+		close(s.send)
+		s.conn.Close()
+	})
+}
+
+// flushStop emits a final stop CallEvent for this subscriber's session, used
+// during graceful shutdown so the drain window's WS streams leave a clean
+// record in Redis and the FMS publisher rather than just dropping. ctx
+// bounds the Redis write by the caller's drain deadline rather than opening
+// a fresh unbounded-relative-to-shutdown timeout per subscriber.
+func (s *subscriber) flushStop(ctx context.Context) {
+	event := CallEvent{
+		SessionID: s.sessionID,
+		AgentID:   s.agentID,
+		EventType: "stop",
+		Timestamp: time.Now(),
+	}
+	recordCallEventContext(ctx, event)
+	publishToFMS(event)
+}
+
+// eventHub fans CallEvents out to every subscriber watching a session or
+// agent. It is the shared sink for both the HTTP callEventHandler and the WS
+// handler, so subscribers see a single consistent order of events.
+type eventHub struct {
+	mu        sync.RWMutex
+	bySession map[string]map[*subscriber]struct{}
+	byAgent   map[string]map[*subscriber]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		bySession: make(map[string]map[*subscriber]struct{}),
+		byAgent:   make(map[string]map[*subscriber]struct{}),
+	}
+}
+
+func (h *eventHub) subscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.bySession[sub.sessionID] == nil {
+		h.bySession[sub.sessionID] = make(map[*subscriber]struct{})
+	}
+	h.bySession[sub.sessionID][sub] = struct{}{}
+
+	if sub.agentID != "" {
+		if h.byAgent[sub.agentID] == nil {
+			h.byAgent[sub.agentID] = make(map[*subscriber]struct{})
+		}
+		h.byAgent[sub.agentID][sub] = struct{}{}
+	}
+}
+
+func (h *eventHub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.bySession[sub.sessionID], sub)
+	if len(h.bySession[sub.sessionID]) == 0 {
+		delete(h.bySession, sub.sessionID)
+	}
+
+	if sub.agentID != "" {
+		delete(h.byAgent[sub.agentID], sub)
+		if len(h.byAgent[sub.agentID]) == 0 {
+			delete(h.byAgent, sub.agentID)
+		}
+	}
+
+	sub.close()
+}
+
+// publish fans event out to subscribers of its session and agent. For a
+// subscriber whose send queue is full the event is dropped rather than
+// blocking the publisher, since a slow WS client shouldn't stall HTTP
+// callers; the subscriber itself stays subscribed.
+func (h *eventHub) publish(event CallEvent) {
+	switch event.EventType {
+	case "quality_report", "start", "stop":
+	default:
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := make(map[*subscriber]struct{})
+	for sub := range h.bySession[event.SessionID] {
+		seen[sub] = struct{}{}
+	}
+	for sub := range h.byAgent[event.AgentID] {
+		seen[sub] = struct{}{}
+	}
+
+	for sub := range seen {
+		select {
+		case sub.send <- event:
+		default:
+			log.Printf("Dropping event for slow WS subscriber (session=%s agent=%s)", sub.sessionID, sub.agentID)
+		}
+	}
+}
+
+// drainAll flushes a final stop event for every open subscriber and closes
+// their connections, called during graceful shutdown so in-flight WS streams
+// don't just vanish mid-drain. It stops as soon as ctx is done rather than
+// working through the remaining subscribers, so a slow/unreachable Redis
+// can't stall shutdown past the configured drain window.
+func (h *eventHub) drainAll(ctx context.Context) {
+	h.mu.Lock()
+	subs := make([]*subscriber, 0)
+	for _, sessionSubs := range h.bySession {
+		for sub := range sessionSubs {
+			subs = append(subs, sub)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		sub.flushStop(ctx)
+		h.unsubscribe(sub)
+	}
+}
+
+// closeSession forcibly disconnects every WebSocket subscriber watching a
+// given session, used by the revocation/expiry paths.
+func (h *eventHub) closeSession(sessionID string) {
+	h.mu.Lock()
+	subs := make([]*subscriber, 0, len(h.bySession[sessionID]))
+	for sub := range h.bySession[sessionID] {
+		subs = append(subs, sub)
+	}
+	h.mu.Unlock()
+
+	for _, sub := range subs {
+		h.unsubscribe(sub)
+	}
+}
+
+// websocketHandler upgrades an authenticated request to a WebSocket, streams
+// inbound CallEvents from the client into the shared event pipeline, and
+// relays events published by the hub back out to the client.
+func websocketHandler(w http.ResponseWriter, r *http.Request) {
+	wsRouter.enter()
+	defer wsRouter.leave()
+
+	vars := mux.Vars(r)
+	sessionID := vars["sessionId"]
+
+	claims, ok := sessionClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Missing session claims", http.StatusUnauthorized)
+		return
+	}
+
+	// A valid token on its own only proves who the caller is, not that they
+	// own this particular session - without this check any authenticated
+	// caller could subscribe to and eavesdrop on someone else's session.
+	owner, ok := sessionOwner(sessionID)
+	if !ok || owner != claims.Subject {
+		http.Error(w, "Not authorized for this session", http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	// net/http arms a write deadline on the connection for the upgrade
+	// request and only clears it when the handler returns - which, for a
+	// hijacked WS connection, is when the session ends. Clear it now and
+	// manage WS write deadlines ourselves in writePump.
+	conn.SetWriteDeadline(time.Time{})
+
+	sub := &subscriber{
+		sessionID: sessionID,
+		agentID:   claims.Subject,
+		conn:      conn,
+		send:      make(chan CallEvent, wsQueueSize),
+	}
+	sub.deadline = newDeadlineTimer(wsPingTimeout, func() {
+		log.Printf("Closing idle WS connection (session=%s agent=%s): no pong within %s", sub.sessionID, sub.agentID, wsPingTimeout)
+		sub.conn.Close()
+	})
+	hub.subscribe(sub)
+	defer hub.unsubscribe(sub)
+
+	go writePump(sub)
+	readPump(sub)
+}
+
+// readPump reads CallEvents sent by the client, stamps and publishes them
+// through the same pipeline callEventHandler uses, then blocks until the
+// connection closes.
+func readPump(sub *subscriber) {
+	sub.conn.SetReadDeadline(time.Now().Add(wsPingTimeout))
+	sub.conn.SetPongHandler(func(string) error {
+		sub.conn.SetReadDeadline(time.Now().Add(wsPingTimeout))
+		sub.deadline.Reset(wsPingTimeout)
+		return nil
+	})
+
+	for {
+		var event CallEvent
+		if err := sub.conn.ReadJSON(&event); err != nil {
+			return
+		}
+
+		event.Timestamp = time.Now()
+		if event.SessionID == "" {
+			event.SessionID = sub.sessionID
+		}
+
+		recordCallEvent(event)
+		publishToFMS(event)
+		hub.publish(event)
+	}
+}
+
+// writePump relays events queued for this subscriber and sends periodic
+// pings, closing the connection if either write fails.
+func writePump(sub *subscriber) {
+	ticker := time.NewTicker(wsPingTimeout / 2)
+	defer ticker.Stop()
+	defer sub.conn.Close()
+
+	for {
+		select {
+		case event, ok := <-sub.send:
+			if !ok {
+				return
+			}
+			sub.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := sub.conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			sub.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := sub.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func debugStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wsRouter.snapshot())
+}
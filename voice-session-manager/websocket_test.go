@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestEventHubPublishFansOutBySessionAndAgent(t *testing.T) {
+	h := newEventHub()
+
+	bySession := &subscriber{sessionID: "sess-1", send: make(chan CallEvent, 1)}
+	byAgent := &subscriber{sessionID: "sess-2", agentID: "agent-1", send: make(chan CallEvent, 1)}
+	unrelated := &subscriber{sessionID: "sess-3", agentID: "agent-2", send: make(chan CallEvent, 1)}
+
+	h.subscribe(bySession)
+	h.subscribe(byAgent)
+	h.subscribe(unrelated)
+
+	h.publish(CallEvent{SessionID: "sess-1", AgentID: "agent-1", EventType: "start"})
+
+	select {
+	case <-bySession.send:
+	default:
+		t.Error("expected subscriber matched on session ID to receive the event")
+	}
+
+	select {
+	case <-byAgent.send:
+	default:
+		t.Error("expected subscriber matched on agent ID to receive the event")
+	}
+
+	select {
+	case <-unrelated.send:
+		t.Error("unrelated subscriber should not receive the event")
+	default:
+	}
+}
+
+func TestEventHubPublishIgnoresUnknownEventTypes(t *testing.T) {
+	h := newEventHub()
+	sub := &subscriber{sessionID: "sess-1", send: make(chan CallEvent, 1)}
+	h.subscribe(sub)
+
+	h.publish(CallEvent{SessionID: "sess-1", EventType: "not_a_real_event"})
+
+	select {
+	case <-sub.send:
+		t.Error("expected non-start/stop/quality_report event types to be dropped")
+	default:
+	}
+}
+
+func TestEventHubPublishDropsOnFullQueue(t *testing.T) {
+	h := newEventHub()
+	sub := &subscriber{sessionID: "sess-1", send: make(chan CallEvent, 1)}
+	h.subscribe(sub)
+
+	// Fill the queue, then publish a second event: the second event should
+	// be dropped for the slow subscriber rather than the publisher blocking.
+	h.publish(CallEvent{SessionID: "sess-1", EventType: "start"})
+	h.publish(CallEvent{SessionID: "sess-1", EventType: "stop"})
+
+	first := <-sub.send
+	if first.EventType != "start" {
+		t.Errorf("expected the first queued event to be 'start', got %q", first.EventType)
+	}
+
+	select {
+	case <-sub.send:
+		t.Error("expected the second event to have been dropped, not queued")
+	default:
+	}
+}